@@ -19,15 +19,17 @@ import (
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
 
 	backuppb "github.com/pingcap/kvproto/pkg/backup"
 	"github.com/pingcap/kvproto/pkg/metapb"
 
 	berrors "github.com/pingcap/br/pkg/errors"
 	"github.com/pingcap/br/pkg/glue"
-	"github.com/pingcap/br/pkg/logutil"
 	"github.com/pingcap/br/pkg/pdutil"
 	"github.com/pingcap/br/pkg/utils"
 )
@@ -35,31 +37,124 @@ import (
 const (
 	dialTimeout = 30 * time.Second
 
-	resetRetryTimes = 3
+	// defaultUnhealthyTTL is how long a pooled conn may stay non-Ready before
+	// Pool.Get gives up on it and force-refreshes it, instead of waiting for
+	// the caller's own RPC to time out against a blackholed peer.
+	defaultUnhealthyTTL = 30 * time.Second
+
+	// healthProbeInterval is how often the background goroutine re-checks
+	// the state of every pooled conn.
+	healthProbeInterval = 15 * time.Second
 )
 
+// connRecord wraps a pooled *grpc.ClientConn with the health bookkeeping used
+// by Pool.Get and the background prober to detect blackholed peers.
+type connRecord struct {
+	conn *grpc.ClientConn
+
+	lastState      connectivity.State
+	lastSuccess    time.Time
+	unhealthySince time.Time
+
+	// probeFailing is set while the latest active probe of this conn failed,
+	// so a Ready state alone doesn't clear the unhealthy watermark.
+	probeFailing bool
+}
+
+// isUnhealthy reports whether this conn has been non-Ready, or failing its
+// active probe, for longer than ttl, i.e. it looks blackholed rather than
+// merely reconnecting.
+func (r *connRecord) isUnhealthy(ttl time.Duration) bool {
+	if r.lastState == connectivity.Ready && !r.probeFailing {
+		return false
+	}
+	return !r.unhealthySince.IsZero() && time.Since(r.unhealthySince) > ttl
+}
+
+// observeState records state as the conn's freshly-observed connectivity
+// state, starting or clearing the unhealthy watermark as appropriate.
+func (r *connRecord) observeState(state connectivity.State) {
+	r.lastState = state
+	if state == connectivity.Ready {
+		r.lastSuccess = time.Now()
+		if !r.probeFailing {
+			r.unhealthySince = time.Time{}
+		}
+		return
+	}
+	if r.unhealthySince.IsZero() {
+		r.unhealthySince = time.Now()
+	}
+}
+
+// observeProbeFailure records that an active probe failed against a conn
+// that otherwise reports connectivity.Ready, so a silent blackhole gets
+// caught even though gRPC's own keepalive hasn't noticed anything wrong.
+func (r *connRecord) observeProbeFailure() {
+	if r.unhealthySince.IsZero() {
+		r.unhealthySince = time.Now()
+	}
+}
+
 // Pool is a lazy pool of gRPC channels.
 // When `Get` called, it lazily allocates new connection if connection not full.
-// If it's full, then it will return allocated channels round-robin.
+// If it's full, then it will return allocated channels round-robin, skipping
+// any conn that looks blackholed per unhealthyTTL.
 type Pool struct {
 	mu sync.Mutex
 
-	conns   []*grpc.ClientConn
+	conns   []*connRecord
 	next    int
 	cap     int
 	newConn func(ctx context.Context) (*grpc.ClientConn, error)
+
+	unhealthyTTL time.Duration
+	probe        ProbeFunc
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// PoolOption configures optional behavior of a Pool created by NewConnPool.
+type PoolOption func(*Pool)
+
+// WithUnhealthyTTL overrides defaultUnhealthyTTL, the duration a pooled conn
+// may stay non-Ready before Get treats it as blackholed.
+func WithUnhealthyTTL(ttl time.Duration) PoolOption {
+	return func(p *Pool) { p.unhealthyTTL = ttl }
+}
+
+// ProbeFunc issues a cheap round-trip RPC against conn to verify it's
+// actually alive. Unlike connectivity.State, which only reflects what
+// gRPC's own keepalive has noticed so far, a ProbeFunc failure is an
+// independent signal: it can catch a silently blackholed TCP peer that
+// gRPC still reports as Ready because no keepalive ping has timed out yet.
+type ProbeFunc func(ctx context.Context, conn *grpc.ClientConn) error
+
+// WithProbe sets the active health probe the background prober issues
+// against every conn on each tick, in addition to passively tracking
+// connectivity state. A nil ProbeFunc (the default) falls back to passive
+// connectivity-state tracking only.
+func WithProbe(probe ProbeFunc) PoolOption {
+	return func(p *Pool) { p.probe = probe }
 }
 
 func (p *Pool) takeConns() (conns []*grpc.ClientConn) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	p.conns, conns = nil, p.conns
-	p.next = 0
+	for _, rec := range p.conns {
+		conns = append(conns, rec.conn)
+	}
+	p.conns, p.next = nil, 0
 	return conns
 }
 
-// Close closes the conn pool.
+// Close closes the conn pool, stopping the background health prober first.
 func (p *Pool) Close() {
+	if p.cancel != nil {
+		p.cancel()
+		p.wg.Wait()
+	}
 	for _, c := range p.takeConns() {
 		if err := c.Close(); err != nil {
 			log.Warn("failed to close clientConn", zap.String("target", c.Target()), zap.Error(err))
@@ -67,7 +162,14 @@ func (p *Pool) Close() {
 	}
 }
 
-// Get tries to get an existing connection from the pool, or make a new one if the pool not full.
+// Get tries to get an existing healthy connection from the pool, or make a
+// new one if the pool not full. A conn that has been non-Ready for longer
+// than unhealthyTTL is skipped and, once every other conn has been tried,
+// force-refreshed via newConn so a single blackholed peer can't wedge the
+// whole pool. The connectivity state used for this check is refreshed right
+// here rather than relying solely on the background prober, so a conn that
+// dropped since the last probe tick doesn't add up to healthProbeInterval of
+// extra latency on the hot path.
 func (p *Pool) Get(ctx context.Context) (*grpc.ClientConn, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -76,24 +178,407 @@ func (p *Pool) Get(ctx context.Context) (*grpc.ClientConn, error) {
 		if err != nil {
 			return nil, err
 		}
-		p.conns = append(p.conns, c)
+		p.conns = append(p.conns, &connRecord{conn: c, lastState: c.GetState()})
 		return c, nil
 	}
 
-	conn := p.conns[p.next]
-	p.next = (p.next + 1) % p.cap
-	return conn, nil
+	start := p.next
+	for {
+		idx := p.next
+		rec := p.conns[idx]
+		p.next = (p.next + 1) % p.cap
+		rec.observeState(rec.conn.GetState())
+		if !rec.isUnhealthy(p.unhealthyTTL) {
+			return rec.conn, nil
+		}
+		if p.next == start {
+			// Every conn in the pool looks blackholed; refresh just this one
+			// rather than redialing the whole pool on every call.
+			return p.refreshLocked(ctx, idx)
+		}
+	}
+}
+
+// refreshLocked redials the conn at idx and closes the old one. Callers must
+// hold p.mu.
+func (p *Pool) refreshLocked(ctx context.Context, idx int) (*grpc.ClientConn, error) {
+	old := p.conns[idx].conn
+	c, err := p.newConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := old.Close(); err != nil {
+		log.Warn("failed to close blackholed clientConn", zap.String("target", old.Target()), zap.Error(err))
+	}
+	p.conns[idx] = &connRecord{conn: c, lastState: c.GetState()}
+	return c, nil
+}
+
+// probeOnce refreshes the cached connectivity state of every pooled conn,
+// additionally issuing the configured ProbeFunc (if any) against conns that
+// report connectivity.Ready, and proactively closes any conn that has been
+// unhealthy for longer than unhealthyTTL, instead of waiting for the next
+// Get call to notice.
+//
+// This deliberately doesn't issue a grpc_health_v1 Check RPC by default:
+// TiKV doesn't serve the standard gRPC health service, so that call would
+// just fail (and log a warning) on every probe. A caller that does have a
+// cheap real RPC to probe with (e.g. a GetStore call) should supply it via
+// WithProbe; without one, connectivity state plus unhealthyTTL is the only
+// blackhole signal available.
+func (p *Pool) probeOnce(ctx context.Context) {
+	p.mu.Lock()
+	recs := make([]*connRecord, len(p.conns))
+	copy(recs, p.conns)
+	probe := p.probe
+	p.mu.Unlock()
+
+	var stale []*connRecord
+	for _, rec := range recs {
+		state := rec.conn.GetState()
+
+		probeFailed := false
+		if state == connectivity.Ready && probe != nil {
+			probeCtx, cancel := context.WithTimeout(ctx, healthProbeInterval/2)
+			if err := probe(probeCtx, rec.conn); err != nil {
+				probeFailed = true
+				log.Warn("pooled conn failed active health probe, may be blackholed",
+					zap.String("target", rec.conn.Target()), zap.Error(err))
+			}
+			cancel()
+		}
+
+		p.mu.Lock()
+		rec.probeFailing = probeFailed
+		rec.observeState(state)
+		if probeFailed {
+			rec.observeProbeFailure()
+		}
+		unhealthy := rec.isUnhealthy(p.unhealthyTTL)
+		p.mu.Unlock()
+
+		if unhealthy {
+			stale = append(stale, rec)
+		}
+	}
+
+	for _, rec := range stale {
+		p.mu.Lock()
+		idx := p.indexOfLocked(rec)
+		if idx >= 0 && p.conns[idx].isUnhealthy(p.unhealthyTTL) {
+			target := rec.conn.Target()
+			if _, err := p.refreshLocked(ctx, idx); err != nil {
+				log.Warn("failed to refresh blackholed pooled conn", zap.String("target", target), zap.Error(err))
+			} else {
+				log.Warn("closed blackholed pooled conn", zap.String("target", target))
+			}
+		}
+		p.mu.Unlock()
+	}
 }
 
-// NewConnPool creates a new Pool by the specified conn factory function and capacity.
-func NewConnPool(cap int, newConn func(ctx context.Context) (*grpc.ClientConn, error)) *Pool {
-	return &Pool{
-		cap:     cap,
-		conns:   make([]*grpc.ClientConn, 0, cap),
-		newConn: newConn,
+// indexOfLocked returns rec's current index in p.conns, or -1 if it's no
+// longer pooled (e.g. another probeOnce pass or a Get already refreshed
+// it). Callers must hold p.mu.
+func (p *Pool) indexOfLocked(rec *connRecord) int {
+	for i, r := range p.conns {
+		if r == rec {
+			return i
+		}
+	}
+	return -1
+}
+
+func (p *Pool) probeLoop(ctx context.Context) {
+	defer p.wg.Done()
+	ticker := time.NewTicker(healthProbeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.probeOnce(ctx)
+		}
+	}
+}
+
+// NewConnPool creates a new Pool by the specified conn factory function and
+// capacity, and starts a background goroutine that periodically probes
+// pooled conns to detect silent TCP blackholes before unhealthyTTL would
+// otherwise force a refresh on the hot path.
+func NewConnPool(cap int, newConn func(ctx context.Context) (*grpc.ClientConn, error), opts ...PoolOption) *Pool {
+	p := &Pool{
+		cap:          cap,
+		conns:        make([]*connRecord, 0, cap),
+		newConn:      newConn,
+		unhealthyTTL: defaultUnhealthyTTL,
 
 		mu: sync.Mutex{},
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.wg.Add(1)
+	go p.probeLoop(ctx)
+	return p
+}
+
+// StoreManager owns the per-store *grpc.ClientConn cache that used to live
+// directly on Mgr. Callers should prefer WithConn over holding on to a raw
+// conn: when the callback reports a gRPC error that means the peer went
+// away (Unavailable, DeadlineExceeded), the conn is evicted and the next
+// WithConn call re-dials it, replacing the old manual ResetBackupClient
+// retry loop.
+type StoreManager struct {
+	pdClient  pd.Client
+	tlsConf   *tls.Config
+	keepalive keepalive.ClientParameters
+
+	mu   sync.Mutex
+	clis map[uint64]*storeConn
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// storeConn wraps a cached *grpc.ClientConn with the watermark needed to
+// tell "just flipped to TransientFailure" apart from "has been stuck in
+// TransientFailure past transientFailureTTL".
+type storeConn struct {
+	conn *grpc.ClientConn
+
+	// transientSince is when this conn was first observed in
+	// TransientFailure by pingIdleConns, zeroed whenever it's next observed
+	// Ready.
+	transientSince time.Time
+}
+
+const (
+	// keepAliveCheckInterval is how often the background goroutine scans
+	// cached conns for ones stuck in TransientFailure.
+	keepAliveCheckInterval = 30 * time.Second
+
+	// transientFailureTTL is how long a cached conn may sit in
+	// TransientFailure before the keep-alive goroutine drops it, so a TiKV
+	// rolling restart doesn't leave a stale entry poisoning later requests.
+	transientFailureTTL = 30 * time.Second
+)
+
+// NewStoreManager creates a StoreManager and starts its keep-alive
+// goroutine.
+func NewStoreManager(pdClient pd.Client, keepalive keepalive.ClientParameters, tlsConf *tls.Config) *StoreManager {
+	sm := &StoreManager{
+		pdClient:  pdClient,
+		tlsConf:   tlsConf,
+		keepalive: keepalive,
+		clis:      make(map[uint64]*storeConn),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	sm.cancel = cancel
+	sm.wg.Add(1)
+	go sm.keepAliveLoop(ctx)
+	return sm
+}
+
+func (sm *StoreManager) dial(ctx context.Context, storeID uint64) (*grpc.ClientConn, error) {
+	store, err := sm.pdClient.GetStore(ctx, storeID)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	opt := grpc.WithInsecure()
+	if sm.tlsConf != nil {
+		opt = grpc.WithTransportCredentials(credentials.NewTLS(sm.tlsConf))
+	}
+	ctx, cancel := context.WithTimeout(ctx, dialTimeout)
+	bfConf := backoff.DefaultConfig
+	bfConf.MaxDelay = time.Second * 3
+	addr := store.GetPeerAddress()
+	if addr == "" {
+		addr = store.GetAddress()
+	}
+	conn, err := grpc.DialContext(
+		ctx,
+		addr,
+		opt,
+		grpc.WithBlock(),
+		grpc.WithConnectParams(grpc.ConnectParams{Backoff: bfConf}),
+		grpc.WithKeepaliveParams(sm.keepalive),
+	)
+	cancel()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return conn, nil
+}
+
+func (sm *StoreManager) getOrDialLocked(ctx context.Context, storeID uint64) (*grpc.ClientConn, error) {
+	if sc, ok := sm.clis[storeID]; ok {
+		return sc.conn, nil
+	}
+	conn, err := sm.dial(ctx, storeID)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	sm.clis[storeID] = &storeConn{conn: conn}
+	return conn, nil
+}
+
+// WithConn runs f against the cached conn for storeID, dialing one first if
+// necessary. If f returns a gRPC error that indicates the peer is
+// unreachable, the conn is evicted so the next WithConn call re-dials it.
+func (sm *StoreManager) WithConn(ctx context.Context, storeID uint64, f func(*grpc.ClientConn) error) error {
+	sm.mu.Lock()
+	conn, err := sm.getOrDialLocked(ctx, storeID)
+	sm.mu.Unlock()
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	err = f(conn)
+	if isRecoverableRPCError(err) {
+		sm.Invalidate(storeID)
+	}
+	return err
+}
+
+// Invalidate drops the cached conn for storeID and closes it, so the next
+// WithConn call re-dials. Unlike RemoveConn it never returns an error, and
+// is meant to be called from a caller's own RPC error handling path.
+func (sm *StoreManager) Invalidate(storeID uint64) {
+	sm.mu.Lock()
+	sc, ok := sm.clis[storeID]
+	if ok {
+		delete(sm.clis, storeID)
+	}
+	sm.mu.Unlock()
+
+	if ok {
+		if err := sc.conn.Close(); err != nil {
+			log.Warn("failed to close invalidated conn, ignore it", zap.Uint64("storeID", storeID), zap.Error(err))
+		}
+	}
+}
+
+// RemoveConn closes and drops the cached conn for storeID, if any.
+func (sm *StoreManager) RemoveConn(ctx context.Context, storeID uint64) error {
+	sm.mu.Lock()
+	sc, ok := sm.clis[storeID]
+	delete(sm.clis, storeID)
+	sm.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return errors.Trace(sc.conn.Close())
+}
+
+func (sm *StoreManager) keepAliveLoop(ctx context.Context) {
+	defer sm.wg.Done()
+	ticker := time.NewTicker(keepAliveCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sm.pingIdleConns(ctx)
+		}
+	}
+}
+
+// pingIdleConns drops cached conns that have been stuck in TransientFailure
+// for longer than transientFailureTTL, so a TiKV rolling restart doesn't
+// leave stale entries poisoning later requests. The TTL is measured from
+// when a conn was first observed in TransientFailure by this goroutine, not
+// by a single blocking probe, so a conn that just flipped to
+// TransientFailure survives until it's genuinely been stuck for the TTL.
+func (sm *StoreManager) pingIdleConns(ctx context.Context) {
+	sm.mu.Lock()
+	candidates := make([]uint64, 0, len(sm.clis))
+	for storeID, sc := range sm.clis {
+		state := sc.conn.GetState()
+		if state == connectivity.Ready {
+			sc.transientSince = time.Time{}
+		}
+		if state != connectivity.TransientFailure {
+			continue
+		}
+		if sc.transientSince.IsZero() {
+			sc.transientSince = time.Now()
+		}
+		candidates = append(candidates, storeID)
+	}
+	sm.mu.Unlock()
+
+	// Probing blocks up to a second per conn; do it without sm.mu held so a
+	// rolling restart with many TransientFailure conns doesn't serialize
+	// every WithConn/Invalidate/RemoveConn call in the meantime.
+	stale := make([]uint64, 0, len(candidates))
+	for _, storeID := range candidates {
+		sm.mu.Lock()
+		sc, ok := sm.clis[storeID]
+		sm.mu.Unlock()
+		if !ok {
+			continue
+		}
+
+		state := sc.conn.GetState()
+		if state == connectivity.TransientFailure {
+			probeCtx, cancel := context.WithTimeout(ctx, time.Second)
+			sc.conn.WaitForStateChange(probeCtx, state)
+			cancel()
+		}
+
+		sm.mu.Lock()
+		stillTransient := sc.conn.GetState() == connectivity.TransientFailure && !sc.transientSince.IsZero()
+		overTTL := stillTransient && time.Since(sc.transientSince) > transientFailureTTL
+		sm.mu.Unlock()
+		if overTTL {
+			stale = append(stale, storeID)
+		}
+	}
+
+	for _, storeID := range stale {
+		log.Warn("dropping store conn stuck in TransientFailure past the TTL",
+			zap.Uint64("storeID", storeID), zap.Duration("ttl", transientFailureTTL))
+		if err := sm.RemoveConn(ctx, storeID); err != nil {
+			log.Warn("failed to close stale conn, ignore it", zap.Uint64("storeID", storeID), zap.Error(err))
+		}
+	}
+}
+
+// Close stops the keep-alive goroutine and closes every cached conn.
+func (sm *StoreManager) Close() {
+	sm.cancel()
+	sm.wg.Wait()
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	for storeID, sc := range sm.clis {
+		if err := sc.conn.Close(); err != nil {
+			log.Error("fail to close store conn", zap.Uint64("storeID", storeID), zap.Error(err))
+		}
+	}
+	sm.clis = make(map[uint64]*storeConn)
+}
+
+// isRecoverableRPCError reports whether err is a gRPC status that indicates
+// the conn itself is unusable rather than the request being invalid.
+// codes.Canceled is deliberately excluded: it means the caller's own ctx
+// was canceled (e.g. the backup was aborted), which says nothing about the
+// health of the store, so it must not mark a healthy store slow or evict
+// its conn.
+func isRecoverableRPCError(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
 }
 
 // Mgr manages connections to a TiDB cluster.
@@ -103,14 +588,115 @@ type Mgr struct {
 	dom       *domain.Domain
 	storage   kv.Storage   // Used to access SQL related interfaces.
 	tikvStore tikv.Storage // Used to access TiKV specific interfaces.
-	grpcClis  struct {
-		mu   sync.Mutex
-		clis map[uint64]*grpc.ClientConn
-	}
+	storeMgr  *StoreManager
+
+	healthMu        sync.RWMutex
+	healthListeners []HealthListener
+	healthStatus    map[uint64]*storeHealthState
+
 	keepalive   keepalive.ClientParameters
 	ownsStorage bool
 }
 
+// StoreHealthFeedback carries the most recently observed RPC outcome for a
+// store, as reported through HealthListener.OnStoreFeedback.
+type StoreHealthFeedback struct {
+	SlowScore uint8
+	LastError error
+	Timestamp time.Time
+}
+
+// HealthListener receives store health feedback aggregated by Mgr, so
+// schedulers can down-weight or evict connections to stores observed to be
+// slow or failing, driven by real RPC outcomes rather than only gRPC-level
+// connection state.
+type HealthListener interface {
+	OnStoreFeedback(storeID uint64, feedback StoreHealthFeedback)
+}
+
+// storeHealthState is the bookkeeping Mgr keeps per store between
+// RecordFeedback calls.
+type storeHealthState struct {
+	errorStreak int
+	feedback    StoreHealthFeedback
+}
+
+const (
+	// slowErrorStreakThreshold is the number of consecutive
+	// Unavailable/DeadlineExceeded errors observed from a store before
+	// RecordFeedback bumps its slow score.
+	slowErrorStreakThreshold = 3
+
+	maxSlowScore uint8 = 100
+)
+
+// RegisterHealthListener adds l to the set of listeners notified whenever
+// RecordFeedback observes a store's health.
+func (mgr *Mgr) RegisterHealthListener(l HealthListener) {
+	mgr.healthMu.Lock()
+	defer mgr.healthMu.Unlock()
+	mgr.healthListeners = append(mgr.healthListeners, l)
+}
+
+// RecordFeedback records the outcome of an RPC to storeID and notifies
+// registered HealthListeners. Backup RPC wrappers call this after each
+// backuppb.BackupClient call: Unavailable/DeadlineExceeded bump an error
+// streak and, once it reaches slowErrorStreakThreshold, bump the store's slow score;
+// any other outcome resets the streak and decays the score. This is also
+// the path that makes GetBackupClient's conn eviction real for RPCs made
+// outside WithConn: an error that looks like the store is unreachable
+// invalidates the cached conn so the next GetBackupClient call re-dials.
+func (mgr *Mgr) RecordFeedback(storeID uint64, err error, latency time.Duration) {
+	mgr.healthMu.Lock()
+	state, ok := mgr.healthStatus[storeID]
+	if !ok {
+		state = &storeHealthState{}
+		mgr.healthStatus[storeID] = state
+	}
+	if isRecoverableRPCError(err) {
+		state.errorStreak++
+		if state.errorStreak >= slowErrorStreakThreshold && state.feedback.SlowScore < maxSlowScore {
+			state.feedback.SlowScore++
+		}
+	} else {
+		state.errorStreak = 0
+		if state.feedback.SlowScore > 0 {
+			state.feedback.SlowScore--
+		}
+	}
+	state.feedback.LastError = err
+	state.feedback.Timestamp = time.Now()
+	feedback := state.feedback
+	listeners := append([]HealthListener(nil), mgr.healthListeners...)
+	mgr.healthMu.Unlock()
+
+	if isRecoverableRPCError(err) {
+		mgr.storeMgr.Invalidate(storeID)
+	}
+
+	for _, l := range listeners {
+		l.OnStoreFeedback(storeID, feedback)
+	}
+}
+
+// GetStoreHealth returns the most recently recorded health feedback for
+// storeID, or the zero value if RecordFeedback has never been called for
+// it.
+func (mgr *Mgr) GetStoreHealth(storeID uint64) StoreHealthFeedback {
+	mgr.healthMu.RLock()
+	defer mgr.healthMu.RUnlock()
+	if state, ok := mgr.healthStatus[storeID]; ok {
+		return state.feedback
+	}
+	return StoreHealthFeedback{}
+}
+
+// IsSlow reports whether storeID has been marked slow by recent
+// RecordFeedback calls.
+func (mgr *Mgr) IsSlow(storeID uint64) bool {
+	return mgr.GetStoreHealth(storeID).SlowScore > 0
+}
+
 // StoreBehavior is the action to do in GetAllTiKVStores when a non-TiKV
 // store (e.g. TiFlash store) is found.
 type StoreBehavior uint8
@@ -125,8 +711,37 @@ const (
 	// TiFlashOnly caused GetAllTiKVStores to skip the store which is not a
 	// TiFlash node.
 	TiFlashOnly StoreBehavior = 2
+	// TiFlashComputeOnly causes GetAllTiKVStores to skip every store except
+	// disaggregated TiFlash compute nodes (engine=tiflash, engine_role=compute).
+	TiFlashComputeOnly StoreBehavior = 3
+	// SkipTiFlashCompute causes GetAllTiKVStores to skip disaggregated
+	// TiFlash compute nodes while keeping TiFlash storage nodes.
+	SkipTiFlashCompute StoreBehavior = 4
 )
 
+// engineRoleLabel is the PD store label that distinguishes a disaggregated
+// TiFlash storage node (engine_role=write) from a TiFlash compute node
+// (engine_role=compute). Stores without the label are storage nodes.
+const engineRoleLabel = "engine_role"
+
+// engineRoleCompute is the engineRoleLabel value used by disaggregated
+// TiFlash compute nodes.
+const engineRoleCompute = "compute"
+
+// isTiFlashComputeStore reports whether store is a disaggregated TiFlash
+// compute node, i.e. engine=tiflash with engine_role=compute.
+func isTiFlashComputeStore(store *metapb.Store) bool {
+	if !utils.IsTiFlash(store) {
+		return false
+	}
+	for _, label := range store.GetLabels() {
+		if label.GetKey() == engineRoleLabel {
+			return label.GetValue() == engineRoleCompute
+		}
+	}
+	return false
+}
+
 // GetAllTiKVStores returns all TiKV stores registered to the PD client. The
 // stores must not be a tombstone and must never contain a label `engine=tiflash`.
 func GetAllTiKVStores(
@@ -156,12 +771,65 @@ func GetAllTiKVStores(
 		if !isTiFlash && storeBehavior == TiFlashOnly {
 			continue
 		}
+		isComputeNode := isTiFlashComputeStore(store)
+		if isComputeNode && storeBehavior == SkipTiFlashCompute {
+			continue
+		}
+		if !isComputeNode && storeBehavior == TiFlashComputeOnly {
+			continue
+		}
 		stores[j] = store
 		j++
 	}
 	return stores[:j], nil
 }
 
+// GetTiFlashComputeStores returns only the disaggregated TiFlash compute
+// nodes (engine=tiflash, engine_role=compute) registered to the PD client,
+// so a disaggregated-aware caller can fan out RPCs to them specifically
+// instead of relying on the binary TiKV-vs-TiFlash split.
+func GetTiFlashComputeStores(ctx context.Context, pdClient pd.Client) ([]*metapb.Store, error) {
+	return GetAllTiKVStores(ctx, pdClient, TiFlashComputeOnly)
+}
+
+// pdStoreInfoGetter is the subset of *pdutil.PdController's API that
+// FilterStoresWithRegions depends on, pulled out so tests can supply a
+// fake instead of a real PD connection.
+type pdStoreInfoGetter interface {
+	GetStoreInfo(ctx context.Context, storeID uint64) (*pdutil.StoreInfo, error)
+}
+
+// FilterStoresWithRegions drops stores that currently hold zero regions
+// from stores, so a just-added, not-yet-populated store doesn't get an
+// equal share of backup tasks and stall the whole backup fetching data
+// cross-node. It queries each store's region count through the PD
+// store-status API, rather than paging through the whole keyspace via
+// ScanRegions, and is safe against transient PD errors: a per-store query
+// failure is logged and that store is kept rather than failing the whole
+// call.
+func FilterStoresWithRegions(
+	ctx context.Context,
+	controller pdStoreInfoGetter,
+	stores []*metapb.Store,
+) ([]*metapb.Store, error) {
+	kept := make([]*metapb.Store, 0, len(stores))
+	for _, store := range stores {
+		info, err := controller.GetStoreInfo(ctx, store.GetId())
+		if err != nil {
+			log.Warn("failed to query store region count, keeping store",
+				zap.Uint64("storeID", store.GetId()), zap.Error(err))
+			kept = append(kept, store)
+			continue
+		}
+		if info.Status.RegionCount <= 0 {
+			log.Info("dropping store with no regions from backup assignment", zap.Uint64("storeID", store.GetId()))
+			continue
+		}
+		kept = append(kept, store)
+	}
+	return kept, nil
+}
+
 // NewMgr creates a new Mgr.
 func NewMgr(
 	ctx context.Context,
@@ -173,6 +841,7 @@ func NewMgr(
 	keepalive keepalive.ClientParameters,
 	storeBehavior StoreBehavior,
 	checkRequirements bool,
+	includeEmptyStores bool,
 ) (*Mgr, error) {
 	if span := opentracing.SpanFromContext(ctx); span != nil && span.Tracer() != nil {
 		span1 := span.Tracer().StartSpan("conn.NewMgr", opentracing.ChildOf(span.Context()))
@@ -205,6 +874,16 @@ func NewMgr(
 		log.Error("fail to get store", zap.Error(err))
 		return nil, errors.Trace(err)
 	}
+	// Disaggregated TiFlash compute nodes hold no region peers by design, so
+	// region-count filtering would drop every one of them; only apply it to
+	// behaviors that can return stores actually expected to own regions.
+	if !includeEmptyStores && storeBehavior != TiFlashComputeOnly {
+		stores, err = FilterStoresWithRegions(ctx, controller, stores)
+		if err != nil {
+			log.Error("fail to filter empty stores", zap.Error(err))
+			return nil, errors.Trace(err)
+		}
+	}
 	liveStoreCount := 0
 	for _, s := range stores {
 		if s.GetState() != metapb.StoreState_Up {
@@ -232,94 +911,34 @@ func NewMgr(
 		tlsConf:      tlsConf,
 		ownsStorage:  g.OwnsStorage(),
 	}
-	mgr.grpcClis.clis = make(map[uint64]*grpc.ClientConn)
 	mgr.keepalive = keepalive
+	mgr.storeMgr = NewStoreManager(controller.GetPDClient(), keepalive, tlsConf)
+	mgr.healthStatus = make(map[uint64]*storeHealthState)
 	return mgr, nil
 }
 
-func (mgr *Mgr) getGrpcConnLocked(ctx context.Context, storeID uint64) (*grpc.ClientConn, error) {
-	store, err := mgr.GetPDClient().GetStore(ctx, storeID)
-	if err != nil {
-		return nil, errors.Trace(err)
-	}
-	opt := grpc.WithInsecure()
-	if mgr.tlsConf != nil {
-		opt = grpc.WithTransportCredentials(credentials.NewTLS(mgr.tlsConf))
-	}
-	ctx, cancel := context.WithTimeout(ctx, dialTimeout)
-	bfConf := backoff.DefaultConfig
-	bfConf.MaxDelay = time.Second * 3
-	addr := store.GetPeerAddress()
-	if addr == "" {
-		addr = store.GetAddress()
-	}
-	conn, err := grpc.DialContext(
-		ctx,
-		addr,
-		opt,
-		grpc.WithBlock(),
-		grpc.WithConnectParams(grpc.ConnectParams{Backoff: bfConf}),
-		grpc.WithKeepaliveParams(mgr.keepalive),
-	)
-	cancel()
-	if err != nil {
-		return nil, errors.Trace(err)
-	}
-	return conn, nil
-}
-
-// GetBackupClient get or create a backup client.
+// GetBackupClient get or create a backup client. The backup RPCs made with
+// the returned client aren't wrapped by WithConn, so a failing RPC doesn't
+// evict the conn by itself: callers must report each RPC's outcome via
+// Mgr.RecordFeedback (which invalidates the conn on a store-unreachable
+// error), or call ResetBackupClient directly.
 func (mgr *Mgr) GetBackupClient(ctx context.Context, storeID uint64) (backuppb.BackupClient, error) {
-	mgr.grpcClis.mu.Lock()
-	defer mgr.grpcClis.mu.Unlock()
-
-	if conn, ok := mgr.grpcClis.clis[storeID]; ok {
-		// Find a cached backup client.
-		return backuppb.NewBackupClient(conn), nil
-	}
-
-	conn, err := mgr.getGrpcConnLocked(ctx, storeID)
+	var cli backuppb.BackupClient
+	err := mgr.storeMgr.WithConn(ctx, storeID, func(conn *grpc.ClientConn) error {
+		cli = backuppb.NewBackupClient(conn)
+		return nil
+	})
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	// Cache the conn.
-	mgr.grpcClis.clis[storeID] = conn
-	return backuppb.NewBackupClient(conn), nil
+	return cli, nil
 }
 
 // ResetBackupClient reset the connection for backup client.
 func (mgr *Mgr) ResetBackupClient(ctx context.Context, storeID uint64) (backuppb.BackupClient, error) {
-	mgr.grpcClis.mu.Lock()
-	defer mgr.grpcClis.mu.Unlock()
-
-	if conn, ok := mgr.grpcClis.clis[storeID]; ok {
-		// Find a cached backup client.
-		log.Info("Reset backup client", zap.Uint64("storeID", storeID))
-		err := conn.Close()
-		if err != nil {
-			log.Warn("close backup connection failed, ignore it", zap.Uint64("storeID", storeID))
-		}
-		delete(mgr.grpcClis.clis, storeID)
-	}
-	var (
-		conn *grpc.ClientConn
-		err  error
-	)
-	for retry := 0; retry < resetRetryTimes; retry++ {
-		conn, err = mgr.getGrpcConnLocked(ctx, storeID)
-		if err != nil {
-			log.Warn("failed to reset grpc connection, retry it",
-				zap.Int("retry time", retry), logutil.ShortError(err))
-			time.Sleep(time.Duration(retry+3) * time.Second)
-			continue
-		}
-		mgr.grpcClis.clis[storeID] = conn
-		break
-	}
-	if err != nil {
-		return nil, errors.Trace(err)
-	}
-	return backuppb.NewBackupClient(conn), nil
+	log.Info("Reset backup client", zap.Uint64("storeID", storeID))
+	mgr.storeMgr.Invalidate(storeID)
+	return mgr.GetBackupClient(ctx, storeID)
 }
 
 // GetStorage returns a kv storage.
@@ -344,14 +963,7 @@ func (mgr *Mgr) GetDomain() *domain.Domain {
 
 // Close closes all client in Mgr.
 func (mgr *Mgr) Close() {
-	mgr.grpcClis.mu.Lock()
-	for _, cli := range mgr.grpcClis.clis {
-		err := cli.Close()
-		if err != nil {
-			log.Error("fail to close Mgr", zap.Error(err))
-		}
-	}
-	mgr.grpcClis.mu.Unlock()
+	mgr.storeMgr.Close()
 
 	// Gracefully shutdown domain so it does not affect other TiDB DDL.
 	// Must close domain before closing storage, otherwise it gets stuck forever.
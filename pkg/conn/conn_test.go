@@ -0,0 +1,155 @@
+// Copyright 2020 PingCAP, Inc. Licensed under Apache-2.0.
+
+package conn
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pingcap/errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+
+	"github.com/pingcap/br/pkg/pdutil"
+)
+
+// fakeStoreInfoGetter implements pdStoreInfoGetter with canned per-store
+// responses, so FilterStoresWithRegions can be tested without a real PD.
+type fakeStoreInfoGetter struct {
+	regionCounts map[uint64]int64
+	errs         map[uint64]error
+}
+
+func (f *fakeStoreInfoGetter) GetStoreInfo(_ context.Context, storeID uint64) (*pdutil.StoreInfo, error) {
+	if err, ok := f.errs[storeID]; ok {
+		return nil, err
+	}
+	info := &pdutil.StoreInfo{}
+	info.Status.RegionCount = f.regionCounts[storeID]
+	return info, nil
+}
+
+func TestFilterStoresWithRegionsDropsEmptyStores(t *testing.T) {
+	getter := &fakeStoreInfoGetter{
+		regionCounts: map[uint64]int64{1: 5, 2: 0},
+	}
+	stores := makeStores(1, 2)
+
+	kept, err := FilterStoresWithRegions(context.Background(), getter, stores)
+	require.NoError(t, err)
+	require.Len(t, kept, 1)
+	require.Equal(t, uint64(1), kept[0].GetId())
+}
+
+func TestFilterStoresWithRegionsKeepsStoreOnTransientError(t *testing.T) {
+	getter := &fakeStoreInfoGetter{
+		regionCounts: map[uint64]int64{1: 5},
+		errs:         map[uint64]error{2: errors.New("PD unavailable")},
+	}
+	stores := makeStores(1, 2)
+
+	kept, err := FilterStoresWithRegions(context.Background(), getter, stores)
+	require.NoError(t, err)
+	require.Len(t, kept, 2)
+}
+
+func newTestMgr() *Mgr {
+	return &Mgr{
+		healthStatus: make(map[uint64]*storeHealthState),
+		storeMgr:     NewStoreManager(nil, keepalive.ClientParameters{}, nil),
+	}
+}
+
+func TestRecordFeedbackMarksSlowAfterErrorStreak(t *testing.T) {
+	mgr := newTestMgr()
+	defer mgr.storeMgr.Close()
+
+	unavailable := status.Error(codes.Unavailable, "store unreachable")
+	for i := 0; i < slowErrorStreakThreshold-1; i++ {
+		mgr.RecordFeedback(1, unavailable, 0)
+		require.False(t, mgr.IsSlow(1))
+	}
+	mgr.RecordFeedback(1, unavailable, 0)
+	require.True(t, mgr.IsSlow(1))
+}
+
+func TestRecordFeedbackDecaysScoreOnSuccess(t *testing.T) {
+	mgr := newTestMgr()
+	defer mgr.storeMgr.Close()
+
+	unavailable := status.Error(codes.Unavailable, "store unreachable")
+	for i := 0; i < slowErrorStreakThreshold; i++ {
+		mgr.RecordFeedback(1, unavailable, 0)
+	}
+	require.True(t, mgr.IsSlow(1))
+
+	mgr.RecordFeedback(1, nil, 0)
+	require.False(t, mgr.IsSlow(1))
+}
+
+func TestRecordFeedbackIgnoresClientCancellation(t *testing.T) {
+	mgr := newTestMgr()
+	defer mgr.storeMgr.Close()
+
+	canceled := status.Error(codes.Canceled, "context canceled")
+	for i := 0; i < slowErrorStreakThreshold+1; i++ {
+		mgr.RecordFeedback(1, canceled, 0)
+	}
+	require.False(t, mgr.IsSlow(1))
+}
+
+func TestRecordFeedbackInvalidatesConnOnStoreError(t *testing.T) {
+	mgr := newTestMgr()
+	defer mgr.storeMgr.Close()
+
+	// A non-blocking dial so Invalidate has a real *grpc.ClientConn to close.
+	conn, err := grpc.Dial("127.0.0.1:0", grpc.WithInsecure())
+	require.NoError(t, err)
+	mgr.storeMgr.mu.Lock()
+	mgr.storeMgr.clis[1] = &storeConn{conn: conn}
+	mgr.storeMgr.mu.Unlock()
+
+	mgr.RecordFeedback(1, status.Error(codes.Unavailable, "unreachable"), 0)
+
+	mgr.storeMgr.mu.Lock()
+	_, ok := mgr.storeMgr.clis[1]
+	mgr.storeMgr.mu.Unlock()
+	require.False(t, ok)
+}
+
+// TestWithConnEvictsOnRecoverableErrorNotOnCanceled pins WithConn's own
+// eviction behavior directly, rather than only exercising it indirectly
+// through RecordFeedback: Unavailable/DeadlineExceeded must evict the
+// cached conn so the next call re-dials, while Canceled (the caller's own
+// ctx being canceled) must leave it cached.
+func TestWithConnEvictsOnRecoverableErrorNotOnCanceled(t *testing.T) {
+	sm := NewStoreManager(nil, keepalive.ClientParameters{}, nil)
+	defer sm.Close()
+
+	conn, err := grpc.Dial("127.0.0.1:0", grpc.WithInsecure())
+	require.NoError(t, err)
+	sm.mu.Lock()
+	sm.clis[1] = &storeConn{conn: conn}
+	sm.mu.Unlock()
+
+	err = sm.WithConn(context.Background(), 1, func(*grpc.ClientConn) error {
+		return status.Error(codes.Canceled, "context canceled")
+	})
+	require.Error(t, err)
+	sm.mu.Lock()
+	_, ok := sm.clis[1]
+	sm.mu.Unlock()
+	require.True(t, ok, "Canceled must not evict the cached conn")
+
+	err = sm.WithConn(context.Background(), 1, func(*grpc.ClientConn) error {
+		return status.Error(codes.Unavailable, "store unreachable")
+	})
+	require.Error(t, err)
+	sm.mu.Lock()
+	_, ok = sm.clis[1]
+	sm.mu.Unlock()
+	require.False(t, ok, "Unavailable must evict the cached conn")
+}
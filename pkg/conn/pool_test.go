@@ -0,0 +1,131 @@
+// Copyright 2020 PingCAP, Inc. Licensed under Apache-2.0.
+
+package conn
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+func TestPoolGetReusesConnUnderUnhealthyTTL(t *testing.T) {
+	var dialCount int32
+	newConn := func(ctx context.Context) (*grpc.ClientConn, error) {
+		atomic.AddInt32(&dialCount, 1)
+		return grpc.Dial("127.0.0.1:0", grpc.WithInsecure())
+	}
+
+	pool := NewConnPool(1, newConn)
+	defer pool.Close()
+
+	first, err := pool.Get(context.Background())
+	require.NoError(t, err)
+	second, err := pool.Get(context.Background())
+	require.NoError(t, err)
+
+	// The dialed conn to an unroutable port is never Ready, but it was just
+	// created, so it must not be evicted before unhealthyTTL elapses.
+	require.Same(t, first, second)
+	require.EqualValues(t, 1, atomic.LoadInt32(&dialCount))
+}
+
+func TestPoolGetForceRefreshesConnStuckNonReadyPastTTL(t *testing.T) {
+	var dialCount int32
+	newConn := func(ctx context.Context) (*grpc.ClientConn, error) {
+		atomic.AddInt32(&dialCount, 1)
+		return grpc.Dial("127.0.0.1:0", grpc.WithInsecure())
+	}
+
+	pool := NewConnPool(1, newConn, WithUnhealthyTTL(time.Millisecond))
+	defer pool.Close()
+
+	first, err := pool.Get(context.Background())
+	require.NoError(t, err)
+	require.EqualValues(t, 1, atomic.LoadInt32(&dialCount))
+
+	// Backdate the watermark so the conn looks like it's been blackholed
+	// since well before unhealthyTTL, without waiting on real time.
+	pool.mu.Lock()
+	pool.conns[0].unhealthySince = time.Now().Add(-time.Hour)
+	pool.mu.Unlock()
+
+	second, err := pool.Get(context.Background())
+	require.NoError(t, err)
+	require.NotSame(t, first, second)
+	require.EqualValues(t, 2, atomic.LoadInt32(&dialCount))
+}
+
+func TestPoolProbeOnceMarksReadyConnUnhealthyOnActiveProbeFailure(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	newConn := func(ctx context.Context) (*grpc.ClientConn, error) {
+		return grpc.DialContext(ctx, lis.Addr().String(), grpc.WithInsecure(), grpc.WithBlock())
+	}
+
+	probeErr := errors.New("blackholed")
+	pool := NewConnPool(1, newConn, WithProbe(func(ctx context.Context, conn *grpc.ClientConn) error {
+		return probeErr
+	}))
+	defer pool.Close()
+
+	conn, err := pool.Get(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, connectivity.Ready, conn.GetState())
+
+	pool.probeOnce(context.Background())
+
+	pool.mu.Lock()
+	unhealthySince := pool.conns[0].unhealthySince
+	pool.mu.Unlock()
+	require.False(t, unhealthySince.IsZero(),
+		"an active probe failure must mark a conn unhealthy even though gRPC still reports it Ready")
+}
+
+func TestPoolProbeOnceRefreshesConnThatFailsProbePastTTL(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	var dialCount int32
+	newConn := func(ctx context.Context) (*grpc.ClientConn, error) {
+		atomic.AddInt32(&dialCount, 1)
+		return grpc.DialContext(ctx, lis.Addr().String(), grpc.WithInsecure(), grpc.WithBlock())
+	}
+
+	probeErr := errors.New("blackholed")
+	pool := NewConnPool(1, newConn, WithUnhealthyTTL(time.Millisecond), WithProbe(func(ctx context.Context, conn *grpc.ClientConn) error {
+		return probeErr
+	}))
+	defer pool.Close()
+
+	first, err := pool.Get(context.Background())
+	require.NoError(t, err)
+	require.EqualValues(t, 1, atomic.LoadInt32(&dialCount))
+
+	pool.probeOnce(context.Background())
+	// Backdate the watermark the probe failure just set, so the next probe
+	// sees it as over unhealthyTTL without a real sleep.
+	pool.mu.Lock()
+	pool.conns[0].unhealthySince = time.Now().Add(-time.Hour)
+	pool.mu.Unlock()
+
+	pool.probeOnce(context.Background())
+
+	require.EqualValues(t, 2, atomic.LoadInt32(&dialCount))
+	second, err := pool.Get(context.Background())
+	require.NoError(t, err)
+	require.NotSame(t, first, second)
+}
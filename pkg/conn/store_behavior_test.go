@@ -0,0 +1,99 @@
+// Copyright 2020 PingCAP, Inc. Licensed under Apache-2.0.
+
+package conn
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/stretchr/testify/require"
+	pd "github.com/tikv/pd/client"
+)
+
+// fakeStoresPDClient implements pd.Client with a canned GetAllStores
+// response, so GetAllTiKVStores can be tested without a real PD.
+type fakeStoresPDClient struct {
+	pd.Client
+	stores []*metapb.Store
+}
+
+func (f *fakeStoresPDClient) GetAllStores(_ context.Context, _ ...pd.GetStoreOption) ([]*metapb.Store, error) {
+	return f.stores, nil
+}
+
+func tiflashStore(id uint64, engineRole string) *metapb.Store {
+	labels := []*metapb.StoreLabel{{Key: "engine", Value: "tiflash"}}
+	if engineRole != "" {
+		labels = append(labels, &metapb.StoreLabel{Key: engineRoleLabel, Value: engineRole})
+	}
+	return &metapb.Store{Id: id, Labels: labels}
+}
+
+func TestIsTiFlashComputeStore(t *testing.T) {
+	cases := []struct {
+		name  string
+		store *metapb.Store
+		want  bool
+	}{
+		{"plain tikv store", &metapb.Store{Id: 1}, false},
+		{"tiflash storage store (engine_role=write)", tiflashStore(2, "write"), false},
+		{"tiflash compute store", tiflashStore(3, "compute"), true},
+		{"tiflash store with no engine_role label", tiflashStore(4, ""), false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Equal(t, c.want, isTiFlashComputeStore(c.store))
+		})
+	}
+}
+
+func TestGetAllTiKVStoresBehaviorRouting(t *testing.T) {
+	tikvStore := &metapb.Store{Id: 1}
+	tiflashStorage := tiflashStore(2, "write")
+	tiflashCompute := tiflashStore(3, "compute")
+	allStores := []*metapb.Store{tikvStore, tiflashStorage, tiflashCompute}
+
+	cases := []struct {
+		name     string
+		behavior StoreBehavior
+		wantIDs  []uint64
+		wantErr  bool
+	}{
+		{"ErrorOnTiFlash errors as soon as a TiFlash store is seen", ErrorOnTiFlash, nil, true},
+		{"SkipTiFlash keeps only the plain TiKV store", SkipTiFlash, []uint64{1}, false},
+		{"TiFlashOnly keeps both TiFlash roles", TiFlashOnly, []uint64{2, 3}, false},
+		{"TiFlashComputeOnly keeps only the compute node", TiFlashComputeOnly, []uint64{3}, false},
+		{"SkipTiFlashCompute keeps TiKV and TiFlash storage, drops compute", SkipTiFlashCompute, []uint64{1, 2}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fake := &fakeStoresPDClient{stores: append([]*metapb.Store(nil), allStores...)}
+			got, err := GetAllTiKVStores(context.Background(), fake, c.behavior)
+			if c.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			gotIDs := make([]uint64, 0, len(got))
+			for _, s := range got {
+				gotIDs = append(gotIDs, s.GetId())
+			}
+			require.ElementsMatch(t, c.wantIDs, gotIDs)
+		})
+	}
+}
+
+func TestGetTiFlashComputeStoresReturnsOnlyComputeNodes(t *testing.T) {
+	fake := &fakeStoresPDClient{stores: []*metapb.Store{
+		{Id: 1},
+		tiflashStore(2, "write"),
+		tiflashStore(3, "compute"),
+	}}
+
+	got, err := GetTiFlashComputeStores(context.Background(), fake)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.Equal(t, uint64(3), got[0].GetId())
+}
@@ -0,0 +1,138 @@
+// Copyright 2020 PingCAP, Inc. Licensed under Apache-2.0.
+
+package conn
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"sort"
+	"sync"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+// defaultVirtualNodes is the number of ring positions each store is hashed
+// to when the caller doesn't specify one, giving a reasonably even key
+// distribution without an excessive ring size.
+const defaultVirtualNodes = 100
+
+// StoreSelector deterministically maps a key (e.g. a region ID or key-range
+// boundary) onto one of a set of live TiKV stores using a consistent-hash
+// ring, so backup fan-out keeps routing the same key to the same store as
+// stores join or leave mid-backup, instead of the remapping storm a plain
+// modulo assignment would cause.
+type StoreSelector struct {
+	mu sync.Mutex
+
+	vnodes      int
+	maxInflight int
+
+	ring        []uint32
+	hashToStore map[uint32]uint64
+	stores      map[uint64]*metapb.Store
+	inflight    map[uint64]int
+}
+
+// NewStoreSelector builds a StoreSelector over stores, hashing each store
+// to vnodes positions on the ring. vnodes <= 0 uses defaultVirtualNodes.
+func NewStoreSelector(stores []*metapb.Store, vnodes int) *StoreSelector {
+	if vnodes <= 0 {
+		vnodes = defaultVirtualNodes
+	}
+	s := &StoreSelector{
+		vnodes:   vnodes,
+		inflight: make(map[uint64]int),
+	}
+	s.Update(stores)
+	return s
+}
+
+// WithMaxInflight sets the per-store in-flight task cap: once a picked
+// store is already carrying maxInflight tasks, Pick spills to the next
+// store on the ring instead. A cap <= 0 (the default) disables spilling.
+func (s *StoreSelector) WithMaxInflight(maxInflight int) *StoreSelector {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxInflight = maxInflight
+	return s
+}
+
+// Update rebuilds the ring for the given stores. Because each store's
+// virtual nodes always hash to the same ring positions, stores unaffected
+// by the change keep the keys they already own, so joining or leaving
+// stores only remap the keys adjacent to them on the ring.
+func (s *StoreSelector) Update(stores []*metapb.Store) {
+	ring := make([]uint32, 0, len(stores)*s.vnodes)
+	hashToStore := make(map[uint32]uint64, len(stores)*s.vnodes)
+	storeByID := make(map[uint64]*metapb.Store, len(stores))
+	for _, store := range stores {
+		storeByID[store.GetId()] = store
+		for replica := 0; replica < s.vnodes; replica++ {
+			h := hashVirtualNode(store.GetId(), replica)
+			ring = append(ring, h)
+			hashToStore[h] = store.GetId()
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool { return ring[i] < ring[j] })
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ring = ring
+	s.hashToStore = hashToStore
+	s.stores = storeByID
+	for id := range s.inflight {
+		if _, ok := storeByID[id]; !ok {
+			delete(s.inflight, id)
+		}
+	}
+}
+
+// hashVirtualNode hashes the replica-th virtual node of storeID onto the
+// ring.
+func hashVirtualNode(storeID uint64, replica int) uint32 {
+	buf := make([]byte, 12)
+	binary.BigEndian.PutUint64(buf[:8], storeID)
+	binary.BigEndian.PutUint32(buf[8:], uint32(replica))
+	return crc32.ChecksumIEEE(buf)
+}
+
+// Pick returns the store that owns key on the ring, spilling to the next
+// ring node if that store is at or over the in-flight cap. It returns nil
+// if the selector has no stores.
+func (s *StoreSelector) Pick(key []byte) *metapb.Store {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.ring) == 0 {
+		return nil
+	}
+
+	h := crc32.ChecksumIEEE(key)
+	start := sort.Search(len(s.ring), func(i int) bool { return s.ring[i] >= h })
+
+	var fallback uint64
+	for step := 0; step < len(s.ring); step++ {
+		idx := (start + step) % len(s.ring)
+		storeID := s.hashToStore[s.ring[idx]]
+		if step == 0 {
+			fallback = storeID
+		}
+		if s.maxInflight <= 0 || s.inflight[storeID] < s.maxInflight {
+			s.inflight[storeID]++
+			return s.stores[storeID]
+		}
+	}
+	// Every store on the ring is over the cap; fall back to the originally
+	// picked one rather than refusing to assign work.
+	s.inflight[fallback]++
+	return s.stores[fallback]
+}
+
+// Done decrements the in-flight task count recorded for storeID, letting
+// future Pick calls route to it again once it's back under the cap.
+func (s *StoreSelector) Done(storeID uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inflight[storeID] > 0 {
+		s.inflight[storeID]--
+	}
+}